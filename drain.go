@@ -0,0 +1,91 @@
+package serverctx
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// ShutdownError is returned by RunWithOptions when the shutdown
+// timeout elapsed and remaining connections had to be force-closed
+// via s.Close(). It wraps the deadline error (from s.Shutdown, or
+// from the shutdown context itself when s.Shutdown returned nil but
+// hijacked connections were still open) alongside how many
+// connections were forced shut.
+type ShutdownError struct {
+	Err         error
+	ForceClosed int
+}
+
+func (e *ShutdownError) Error() string {
+	return fmt.Sprintf("%v (force-closed %d connection(s) after shutdown timeout)", e.Err, e.ForceClosed)
+}
+
+func (e *ShutdownError) Unwrap() error {
+	return e.Err
+}
+
+// connTracker records the connections s.ConnState reports as open
+// so RunWithOptions knows how many are left to force-close if the
+// shutdown timeout elapses.
+type connTracker struct {
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+func newConnTracker() *connTracker {
+	return &connTracker{conns: make(map[net.Conn]struct{})}
+}
+
+func (t *connTracker) add(c net.Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.conns[c] = struct{}{}
+}
+
+func (t *connTracker) remove(c net.Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.conns, c)
+}
+
+func (t *connTracker) len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.conns)
+}
+
+// closeAll force-closes every tracked conn directly. This is needed
+// alongside s.Close() because a hijacked conn (as h2c and websocket
+// handlers use to take a connection over raw) is no longer managed
+// by s once hijacked, so s.Close() can't reach it; closing our own
+// reference is the only way to unblock it.
+func (t *connTracker) closeAll() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for c := range t.conns {
+		c.Close()
+	}
+}
+
+// trackConnState chains a ConnState hook that feeds t onto
+// whatever ConnState s already had set, so multiple callers
+// composing options don't clobber each other. Hijacked conns are
+// kept in t rather than dropped: net/http stops reporting state
+// for them once hijacked, so they'd otherwise never be known about
+// again, which is exactly when force-closing them matters most.
+func trackConnState(s *http.Server, t *connTracker) {
+	orig := s.ConnState
+	s.ConnState = func(c net.Conn, state http.ConnState) {
+		switch state {
+		case http.StateNew, http.StateActive, http.StateIdle, http.StateHijacked:
+			t.add(c)
+		case http.StateClosed:
+			t.remove(c)
+		}
+		if orig != nil {
+			orig(c, state)
+		}
+	}
+}
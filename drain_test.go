@@ -0,0 +1,52 @@
+package serverctx
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestForceCloseOnTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block := make(chan struct{})
+	defer close(block)
+	s := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block // simulates a client that never disconnects
+	})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- RunWithOptions(ctx, s, WithShutdownTimeout(100*time.Millisecond), WithListener(l))
+	}()
+
+	client := &http.Client{Timeout: 2 * time.Second}
+	go client.Get("http://" + l.Addr().String())
+
+	time.Sleep(20 * time.Millisecond) // let the request reach the handler
+	cancel()
+
+	var shutdownErr error
+	select {
+	case shutdownErr = <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("RunWithOptions did not return; shutdown timeout failed to force-close the stuck connection")
+	}
+
+	var se *ShutdownError
+	if !errors.As(shutdownErr, &se) {
+		t.Fatalf("got error %v (%T), want a *ShutdownError", shutdownErr, shutdownErr)
+	}
+	if se.ForceClosed < 1 {
+		t.Fatalf("ForceClosed = %d, want at least 1", se.ForceClosed)
+	}
+}
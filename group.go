@@ -0,0 +1,87 @@
+package serverctx
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Group coordinates the lifecycle of several http.Servers under a
+// single context, e.g. a public HTTPS listener alongside an
+// internal metrics/admin listener and a debug/pprof server.
+type Group struct {
+	mu      sync.Mutex
+	servers []groupServer
+}
+
+type groupServer struct {
+	listener net.Listener
+	server   *http.Server
+	opts     []Option
+}
+
+// Add registers s to be run as part of the Group, using
+// s.ListenAndServe (or ListenAndServeTLS, via WithTLS in opts).
+func (g *Group) Add(s *http.Server, opts ...Option) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.servers = append(g.servers, groupServer{server: s, opts: opts})
+}
+
+// AddListener registers s to be run as part of the Group, serving
+// on l instead of calling ListenAndServe.
+func (g *Group) AddListener(l net.Listener, s *http.Server, opts ...Option) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.servers = append(g.servers, groupServer{listener: l, server: s, opts: opts})
+}
+
+// Run starts every registered server concurrently. If any of them
+// fails to start, Run cancels the rest and returns once they've all
+// unwound. Otherwise, when ctx is done, Run calls Shutdown on every
+// server in parallel, bounded by the shared timeout, and aggregates
+// whatever errors come back via errors.Join.
+func (g *Group) Run(ctx context.Context, timeout time.Duration) error {
+	g.mu.Lock()
+	servers := append([]groupServer(nil), g.servers...)
+	g.mu.Unlock()
+
+	if len(servers) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		i   int
+		err error
+	}
+	resCh := make(chan result, len(servers))
+	for i, gs := range servers {
+		i, gs := i, gs
+		go func() {
+			opts := append(append([]Option{}, gs.opts...), WithShutdownTimeout(timeout))
+			if gs.listener != nil {
+				opts = append(opts, WithListener(gs.listener))
+			}
+			resCh <- result{i, RunWithOptions(ctx, gs.server, opts...)}
+		}()
+	}
+
+	errs := make([]error, len(servers))
+	for range servers {
+		r := <-resCh
+		errs[r.i] = r.err
+		if r.err != nil && ctx.Err() == nil && !errors.Is(r.err, http.ErrServerClosed) {
+			// One server failed before shutdown was ever requested
+			// (e.g. "address already in use"); cancel the rest so
+			// they shut down gracefully instead of running orphaned.
+			cancel()
+		}
+	}
+	return errors.Join(errs...)
+}
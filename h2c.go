@@ -0,0 +1,16 @@
+package serverctx
+
+import (
+	"net/http"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// wrapH2C wraps s.Handler with h2c.NewHandler when WithH2C was
+// given, so the server also accepts HTTP/2 cleartext requests.
+func wrapH2C(s *http.Server, o options) {
+	if o.h2c {
+		s.Handler = h2c.NewHandler(s.Handler, &http2.Server{})
+	}
+}
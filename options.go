@@ -0,0 +1,111 @@
+package serverctx
+
+import (
+	"net"
+	"time"
+)
+
+// options holds the configuration assembled by Option values
+// passed to RunWithOptions.
+type options struct {
+	timeout             time.Duration
+	certFile            string
+	keyFile             string
+	listener            net.Listener
+	beforeShutdown      func() bool
+	onShutdown          func()
+	afterShutdown       func(error)
+	forceCloseOnTimeout *bool
+	h2c                 bool
+}
+
+// Option configures the behavior of RunWithOptions.
+type Option func(*options)
+
+// WithShutdownTimeout sets how long Shutdown is given to
+// drain in-flight requests before RunWithOptions gives up
+// and returns the deadline error (wrapped in a *ShutdownError
+// when remaining connections are force-closed; see
+// WithForceCloseOnTimeout).
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithTLS configures RunWithOptions to call ListenAndServeTLS
+// (or ServeTLS, if a listener was also provided) with the
+// given cert and key files instead of ListenAndServe.
+func WithTLS(certFile, keyFile string) Option {
+	return func(o *options) {
+		o.certFile = certFile
+		o.keyFile = keyFile
+	}
+}
+
+// WithListener configures RunWithOptions to serve on the given
+// net.Listener instead of calling ListenAndServe(TLS) on s.
+func WithListener(l net.Listener) Option {
+	return func(o *options) {
+		o.listener = l
+	}
+}
+
+// WithBeforeShutdown registers a callback invoked the moment
+// ctx.Done() is observed, before Shutdown is called. Returning
+// false vetoes the shutdown: RunWithOptions keeps serving and
+// rechecks the callback every beforeShutdownPollInterval until it
+// returns true, at which point shutdown proceeds as normal.
+func WithBeforeShutdown(fn func() bool) Option {
+	return func(o *options) {
+		o.beforeShutdown = fn
+	}
+}
+
+// WithOnShutdown registers a callback invoked right after
+// ctx.Done() is observed and any BeforeShutdown veto has been
+// cleared, just before Shutdown is called on s. This is the
+// place to notify long-lived connections (websockets, SSE) that
+// they should reconnect elsewhere.
+func WithOnShutdown(fn func()) Option {
+	return func(o *options) {
+		o.onShutdown = fn
+	}
+}
+
+// WithAfterShutdown registers a callback invoked once s.Shutdown
+// returns and any force-close has run, with the error
+// RunWithOptions is about to return: s.Shutdown's own error, or a
+// *ShutdownError if connections were force-closed (nil on a clean
+// shutdown).
+func WithAfterShutdown(fn func(error)) Option {
+	return func(o *options) {
+		o.afterShutdown = fn
+	}
+}
+
+// WithForceCloseOnTimeout controls whether RunWithOptions calls
+// s.Close() to forcibly terminate remaining connections once the
+// shutdown timeout elapses. It defaults to true: without it,
+// Shutdown returns its deadline error and long-lived clients that
+// never disconnect are left with their connections open. When conns are
+// force-closed, the returned error is a *ShutdownError reporting
+// both the original deadline error and how many were closed.
+func WithForceCloseOnTimeout(b bool) Option {
+	return func(o *options) {
+		o.forceCloseOnTimeout = &b
+	}
+}
+
+// WithH2C enables serving HTTP/2 cleartext (h2c) alongside plain
+// HTTP/1.1 by wrapping s.Handler with h2c.NewHandler. h2c serves
+// requests over a hijacked connection, which the rest of
+// RunWithOptions' conn tracking treats like any other connection:
+// it counts toward the ForceClosed total in ShutdownError and is
+// force-closed on timeout same as WithForceCloseOnTimeout governs
+// for everything else.
+func WithH2C() Option {
+	return func(o *options) {
+		o.h2c = true
+	}
+}
@@ -0,0 +1,48 @@
+package serverctx
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithBeforeShutdownRetry(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &http.Server{Handler: http.NewServeMux()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls int32
+	const vetoesBeforeAllow = 2
+	before := func() bool {
+		return atomic.AddInt32(&calls, 1) > vetoesBeforeAllow
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- RunWithOptions(ctx, s, WithShutdownTimeout(time.Second), WithListener(l), WithBeforeShutdown(before))
+	}()
+
+	time.Sleep(20 * time.Millisecond) // let listenAndServe start
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("RunWithOptions returned error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("RunWithOptions never returned after the veto was lifted")
+	}
+
+	if got := atomic.LoadInt32(&calls); got <= vetoesBeforeAllow {
+		t.Fatalf("beforeShutdown called %d times, want more than %d (a veto should be retried, not abandoned)", got, vetoesBeforeAllow)
+	}
+}
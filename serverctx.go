@@ -15,60 +15,152 @@ import (
 // errors will be returned whether it's
 // on startup or on shutdown.
 func Run(ctx context.Context, s *http.Server, timeout time.Duration) error {
-	return RunTLS(ctx, s, timeout, "", "")
+	return RunWithOptions(ctx, s, WithShutdownTimeout(timeout))
 }
 
 // RunTLS is like Run but calls ListenAndServeTLS instead.
 func RunTLS(ctx context.Context, s *http.Server, timeout time.Duration, certFile, keyFile string) error {
-	serverErr := make(chan error, 1)
-	go func() {
-		// Capture ListenAndServe errors such as "port already in use".
-		// However, when a server is gracefully shutdown, it is safe to ignore errors
-		// returned from this method (given the select logic below), because
-		// Shutdown causes ListenAndServe to always return http.ErrServerClosed.
-		if certFile != "" && keyFile != "" {
-			serverErr <- s.ListenAndServeTLS(certFile, keyFile)
-		} else {
-			serverErr <- s.ListenAndServe()
-		}
-	}()
-	var err error
-	select {
-	case <-ctx.Done():
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
-		defer cancel()
-		err = s.Shutdown(ctx)
-	case err = <-serverErr:
-	}
-	return err
+	return RunWithOptions(ctx, s, WithShutdownTimeout(timeout), WithTLS(certFile, keyFile))
 }
 
 // Serve is like Run but accepts a custom net.Listener to mimic net/http's Serve behavior.
 func Serve(ctx context.Context, l net.Listener, s *http.Server, timeout time.Duration) error {
-	return ServeTLS(ctx, l, s, timeout, "", "")
+	return RunWithOptions(ctx, s, WithShutdownTimeout(timeout), WithListener(l))
 }
 
 // ServeTLS is like Serve but calls ServeTLS instead.
 func ServeTLS(ctx context.Context, l net.Listener, s *http.Server, timeout time.Duration, certFile, keyFile string) error {
+	return RunWithOptions(ctx, s, WithShutdownTimeout(timeout), WithTLS(certFile, keyFile), WithListener(l))
+}
+
+// RunWithOptions is the configurable variant of Run: it starts s and
+// gracefully shuts it down when ctx is done, with its behavior tuned
+// by the given Options. Use WithListener and WithTLS to pick between
+// ListenAndServe, ListenAndServeTLS, Serve and ServeTLS, and
+// WithBeforeShutdown, WithOnShutdown and WithAfterShutdown to hook
+// into the shutdown lifecycle.
+func RunWithOptions(ctx context.Context, s *http.Server, opts ...Option) error {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	forceClose := true
+	if o.forceCloseOnTimeout != nil {
+		forceClose = *o.forceCloseOnTimeout
+	}
+	tracker := newConnTracker()
+	trackConnState(s, tracker)
+	wrapH2C(s, o)
+
 	serverErr := make(chan error, 1)
 	go func() {
 		// Capture ListenAndServe errors such as "port already in use".
 		// However, when a server is gracefully shutdown, it is safe to ignore errors
 		// returned from this method (given the select logic below), because
 		// Shutdown causes ListenAndServe to always return http.ErrServerClosed.
-		if certFile != "" && keyFile != "" {
-			serverErr <- s.ServeTLS(l, certFile, keyFile)
-		} else {
-			serverErr <- s.Serve(l)
-		}
+		serverErr <- listenAndServe(s, o)
 	}()
+
 	var err error
 	select {
 	case <-ctx.Done():
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		var ok bool
+		err, ok = awaitShutdownApproval(o.beforeShutdown, serverErr)
+		if !ok {
+			break
+		}
+		if o.onShutdown != nil {
+			o.onShutdown()
+		}
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), o.timeout)
 		defer cancel()
-		err = s.Shutdown(ctx)
+		err = s.Shutdown(shutdownCtx)
+		if forceClose {
+			// s.Shutdown doesn't know about hijacked conns (h2c,
+			// websockets, ...), so it can return nil even while some
+			// are still open; give them the rest of the deadline to
+			// drain on their own before forcing the issue.
+			awaitDrain(shutdownCtx, tracker)
+			if n := tracker.len(); n > 0 {
+				s.Close()
+				tracker.closeAll()
+				if err == nil {
+					err = shutdownCtx.Err()
+				}
+				err = &ShutdownError{Err: err, ForceClosed: n}
+			}
+		}
+		if o.afterShutdown != nil {
+			o.afterShutdown(err)
+		}
 	case err = <-serverErr:
 	}
 	return err
 }
+
+// beforeShutdownPollInterval is how often a vetoed shutdown
+// rechecks WithBeforeShutdown before trying again.
+const beforeShutdownPollInterval = 250 * time.Millisecond
+
+// awaitShutdownApproval blocks until before returns true, polling it
+// every beforeShutdownPollInterval, so a veto delays shutdown rather
+// than abandoning it outright. It gives up early if the server exits
+// on its own (e.g. via an external s.Close()) while waiting, in which
+// case ok is false and err is that exit error.
+func awaitShutdownApproval(before func() bool, serverErr <-chan error) (err error, ok bool) {
+	if before == nil {
+		return nil, true
+	}
+	ticker := time.NewTicker(beforeShutdownPollInterval)
+	defer ticker.Stop()
+	for {
+		if before() {
+			return nil, true
+		}
+		select {
+		case err := <-serverErr:
+			return err, false
+		case <-ticker.C:
+		}
+	}
+}
+
+// drainPollInterval is how often awaitDrain rechecks the tracker
+// for remaining open connections.
+const drainPollInterval = 50 * time.Millisecond
+
+// awaitDrain blocks until tracker has no more open conns or ctx is
+// done, whichever comes first. It's a no-op whenever there's
+// nothing left to wait for, which is the common case for servers
+// that don't hijack conns (s.Shutdown already waited for those).
+func awaitDrain(ctx context.Context, tracker *connTracker) {
+	if tracker.len() == 0 {
+		return
+	}
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for tracker.len() > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// listenAndServe picks the right net/http entrypoint on s based
+// on whether a listener and/or TLS cert pair were supplied via
+// Options.
+func listenAndServe(s *http.Server, o options) error {
+	switch {
+	case o.listener != nil && o.certFile != "" && o.keyFile != "":
+		return s.ServeTLS(o.listener, o.certFile, o.keyFile)
+	case o.listener != nil:
+		return s.Serve(o.listener)
+	case o.certFile != "" && o.keyFile != "":
+		return s.ListenAndServeTLS(o.certFile, o.keyFile)
+	default:
+		return s.ListenAndServe()
+	}
+}
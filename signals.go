@@ -0,0 +1,78 @@
+package serverctx
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// RunSignals is like Run but builds its own ctx internally,
+// cancelled on the given signals (SIGINT and SIGTERM if none are
+// given) instead of one passed in by the caller. A second, repeat
+// signal forces s.Close() for operators that don't want to wait
+// out the graceful shutdown timeout.
+func RunSignals(s *http.Server, timeout time.Duration, sigs ...os.Signal) error {
+	return RunTLSSignals(s, timeout, "", "", sigs...)
+}
+
+// RunTLSSignals is like RunSignals but calls ListenAndServeTLS instead.
+func RunTLSSignals(s *http.Server, timeout time.Duration, certFile, keyFile string, sigs ...os.Signal) error {
+	return runSignals(nil, s, timeout, certFile, keyFile, sigs...)
+}
+
+// ServeSignals is like RunSignals but accepts a custom net.Listener
+// to mimic net/http's Serve behavior.
+func ServeSignals(l net.Listener, s *http.Server, timeout time.Duration, sigs ...os.Signal) error {
+	return ServeTLSSignals(l, s, timeout, "", "", sigs...)
+}
+
+// ServeTLSSignals is like ServeSignals but calls ServeTLS instead.
+func ServeTLSSignals(l net.Listener, s *http.Server, timeout time.Duration, certFile, keyFile string, sigs ...os.Signal) error {
+	return runSignals(l, s, timeout, certFile, keyFile, sigs...)
+}
+
+// runSignals builds a ctx cancelled on sigs and runs s under
+// RunWithOptions, forcing s.Close() if sigs fires a second time
+// before shutdown completes.
+func runSignals(l net.Listener, s *http.Server, timeout time.Duration, certFile, keyFile string, sigs ...os.Signal) error {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, sigs...)
+	defer signal.Stop(sigCh)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-sigCh:
+			cancel()
+		case <-done:
+			return
+		}
+		select {
+		case <-sigCh:
+			s.Close()
+		case <-done:
+		}
+	}()
+
+	opts := []Option{WithShutdownTimeout(timeout)}
+	if l != nil {
+		opts = append(opts, WithListener(l))
+	}
+	if certFile != "" && keyFile != "" {
+		opts = append(opts, WithTLS(certFile, keyFile))
+	}
+	return RunWithOptions(ctx, s, opts...)
+}